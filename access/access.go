@@ -0,0 +1,97 @@
+// Package access defines the domain types shared by the fob cache and its
+// pluggable identity Sources (see sources/keycloak, sources/scim, sources/file).
+package access
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AccessUser is a person who has been granted building access.
+type AccessUser struct {
+	UserID   string    `json:"userID"`
+	FobID    int       `json:"fobID,omitempty"`
+	QRID     int       `json:"qrID,omitempty"`
+	TTL      int64     `json:"ttl"`
+	Schedule *Schedule `json:"schedule,omitempty"`
+}
+
+// Schedule is a recurring time-of-day window, e.g. "Mon-Fri 08:00-22:00",
+// outside of which a door controller should refuse access even though the
+// fob itself is still valid.
+type Schedule struct {
+	Days  []time.Weekday `json:"days"`
+	Start string         `json:"start"` // HH:MM, inclusive
+	End   string         `json:"end"`   // HH:MM, exclusive
+}
+
+// Source resolves the set of users that should currently be granted building access.
+type Source interface {
+	ListUsers(ctx context.Context) ([]*AccessUser, error)
+}
+
+// Subscribable is implemented by Sources that can push a refresh signal
+// instead of waiting to be polled on a timer.
+type Subscribable interface {
+	Subscribe(ctx context.Context) (<-chan struct{}, error)
+}
+
+var scheduleWeekdays = map[string]time.Weekday{
+	"Sun": time.Sunday, "Mon": time.Monday, "Tue": time.Tuesday, "Wed": time.Wednesday,
+	"Thu": time.Thursday, "Fri": time.Friday, "Sat": time.Saturday,
+}
+
+// ParseSchedule parses the simple "<days> <start>-<end>" format used by the
+// fobSchedule attribute, e.g. "Mon-Fri 08:00-22:00" or "Mon,Wed,Fri 09:00-17:00".
+func ParseSchedule(s string) (*Schedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("expected format '<days> <start>-<end>', got %q", s)
+	}
+
+	days, err := parseScheduleDays(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	window := strings.SplitN(fields[1], "-", 2)
+	if len(window) != 2 {
+		return nil, fmt.Errorf("expected format '<start>-<end>', got %q", fields[1])
+	}
+
+	return &Schedule{Days: days, Start: window[0], End: window[1]}, nil
+}
+
+func parseScheduleDays(s string) ([]time.Weekday, error) {
+	if start, end, ok := strings.Cut(s, "-"); ok {
+		startDay, known := scheduleWeekdays[start]
+		if !known {
+			return nil, fmt.Errorf("unknown weekday %q", start)
+		}
+		endDay, known := scheduleWeekdays[end]
+		if !known {
+			return nil, fmt.Errorf("unknown weekday %q", end)
+		}
+
+		days := []time.Weekday{}
+		for d := startDay; ; d = (d + 1) % 7 {
+			days = append(days, d)
+			if d == endDay {
+				break
+			}
+		}
+		return days, nil
+	}
+
+	days := []time.Weekday{}
+	for _, name := range strings.Split(s, ",") {
+		d, known := scheduleWeekdays[name]
+		if !known {
+			return nil, fmt.Errorf("unknown weekday %q", name)
+		}
+		days = append(days, d)
+	}
+	return days, nil
+}
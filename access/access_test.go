@@ -0,0 +1,61 @@
+package access
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleDayRange(t *testing.T) {
+	sched, err := ParseSchedule("Mon-Fri 08:00-22:00")
+	if err != nil {
+		t.Fatalf("ParseSchedule: %v", err)
+	}
+	want := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	assertDays(t, sched.Days, want)
+	if sched.Start != "08:00" || sched.End != "22:00" {
+		t.Fatalf("unexpected window: %+v", sched)
+	}
+}
+
+func TestParseScheduleDayRangeWraparound(t *testing.T) {
+	sched, err := ParseSchedule("Fri-Mon 22:00-06:00")
+	if err != nil {
+		t.Fatalf("ParseSchedule: %v", err)
+	}
+	want := []time.Weekday{time.Friday, time.Saturday, time.Sunday, time.Monday}
+	assertDays(t, sched.Days, want)
+}
+
+func TestParseScheduleDayList(t *testing.T) {
+	sched, err := ParseSchedule("Mon,Wed,Fri 09:00-17:00")
+	if err != nil {
+		t.Fatalf("ParseSchedule: %v", err)
+	}
+	want := []time.Weekday{time.Monday, time.Wednesday, time.Friday}
+	assertDays(t, sched.Days, want)
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	cases := []string{
+		"Mon-Fri",
+		"Oogaboog 08:00-22:00",
+		"Mon-Oogaboog 08:00-22:00",
+	}
+	for _, c := range cases {
+		if _, err := ParseSchedule(c); err == nil {
+			t.Errorf("expected error for %q, got nil", c)
+		}
+	}
+}
+
+func assertDays(t *testing.T, got, want []time.Weekday) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
@@ -1,90 +1,50 @@
 package main
 
 import (
-	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
-	"log/slog"
-	"sort"
-	"sync"
-	"time"
-)
 
-type cache struct {
-	keycloak *keycloak
+	"github.com/TheLab.ms/fobsvr/access"
+)
 
-	lock     sync.Mutex
-	state    []*AccessUser
-	hash     string
-	watchers map[chan struct{}]struct{}
+func calculateUsersHash(users []*access.AccessUser) string {
+	js, _ := json.Marshal(&users)
+	hash := sha256.Sum256(js)
+	return hex.EncodeToString(hash[:])
 }
 
-func newCache(k *keycloak) *cache {
-	return &cache{keycloak: k, watchers: map[chan struct{}]struct{}{}}
+// fobsDiff is the added/removed/changed AccessUser entries between two cache snapshots.
+type fobsDiff struct {
+	Added   []*access.AccessUser `json:"added,omitempty"`
+	Removed []*access.AccessUser `json:"removed,omitempty"`
+	Changed []*access.AccessUser `json:"changed,omitempty"`
 }
 
-func (c *cache) Fill() error {
-	ctx, done := context.WithTimeout(context.Background(), time.Minute)
-	defer done()
-
-	users, err := c.keycloak.ListUsers(ctx)
-	if err != nil {
-		return err
+func diffUsers(prev, next []*access.AccessUser) *fobsDiff {
+	prevByID := make(map[string]*access.AccessUser, len(prev))
+	for _, u := range prev {
+		prevByID[u.UserID] = u
 	}
-
-	sort.Slice(users, func(i, j int) bool { return users[i].FobID < users[j].FobID })
-	hash := calculateUsersHash(users)
-
-	c.lock.Lock()
-	defer c.lock.Unlock()
-
-	if c.hash == hash {
-		slog.Info("cache was filled but nothing changed")
-		return nil // nothing has changed
+	nextByID := make(map[string]*access.AccessUser, len(next))
+	for _, u := range next {
+		nextByID[u.UserID] = u
 	}
-	c.state = users
-	c.hash = hash
 
-	for ch := range c.watchers {
-		select {
-		case ch <- struct{}{}:
-		default:
+	diff := &fobsDiff{}
+	for id, u := range nextByID {
+		old, existed := prevByID[id]
+		switch {
+		case !existed:
+			diff.Added = append(diff.Added, u)
+		case calculateUsersHash([]*access.AccessUser{old}) != calculateUsersHash([]*access.AccessUser{u}):
+			diff.Changed = append(diff.Changed, u)
 		}
 	}
-
-	slog.Info("filled cache")
-	return nil
-}
-
-func (c *cache) Load() ([]*AccessUser, string) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	return c.state, c.hash
-}
-
-func (c *cache) Wait(period time.Duration) {
-	ch := make(chan struct{}, 1)
-	c.lock.Lock()
-	c.watchers[ch] = struct{}{}
-	c.lock.Unlock()
-
-	t := time.NewTimer(period)
-	defer t.Stop()
-
-	select {
-	case <-ch:
-	case <-t.C:
+	for id, u := range prevByID {
+		if _, ok := nextByID[id]; !ok {
+			diff.Removed = append(diff.Removed, u)
+		}
 	}
-
-	c.lock.Lock()
-	delete(c.watchers, ch)
-	c.lock.Unlock()
-	close(ch)
-}
-
-func calculateUsersHash(users []*AccessUser) string {
-	js, _ := json.Marshal(&users)
-	hash := sha256.Sum256(js)
-	return hex.EncodeToString(hash[:])
+	return diff
 }
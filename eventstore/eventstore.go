@@ -0,0 +1,144 @@
+// Package eventstore persists door access events and serves paginated,
+// filterable queries over them.
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event represents a single access attempt recorded by a door controller.
+type Event struct {
+	// Timestamp is when the attempt occurred, as Unix milliseconds since the
+	// epoch (i.e. time.Time.UnixMilli) - matching Since/Until on Filter and
+	// the cutoff passed to Purge.
+	Timestamp  int64  `json:"timestamp"`
+	PersonID   string `json:"personID"`
+	FobID      int64  `json:"fobID"`
+	QRID       int64  `json:"qrID"`
+	Authorized bool   `json:"authorized"`
+}
+
+// Filter narrows a Query to a subset of stored events. A zero value matches
+// everything (subject to Limit).
+type Filter struct {
+	PersonID string
+	FobID    int64
+
+	// Since and Until bound Event.Timestamp (Unix milliseconds), inclusive.
+	Since int64
+	Until int64
+
+	Authorized *bool
+
+	Limit  int
+	Offset int
+}
+
+// Store persists Events and serves paginated queries over them.
+type Store interface {
+	Append(ctx context.Context, e *Event) error
+	Query(ctx context.Context, f *Filter) ([]*Event, error)
+
+	// Purge deletes events older than cutoff, returning the number of rows removed.
+	Purge(ctx context.Context, cutoff time.Time) (int64, error)
+
+	Close() error
+}
+
+// RunRetention purges events older than retention once an hour until ctx is canceled.
+// It is a no-op if retention is zero.
+func RunRetention(ctx context.Context, s Store, retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+
+	t := time.NewTicker(time.Hour)
+	defer t.Stop()
+	for {
+		cutoff := time.Now().Add(-retention)
+		n, err := s.Purge(ctx, cutoff)
+		if err != nil {
+			slog.Error("unable to purge lapsed events", "error", err)
+		} else if n > 0 {
+			slog.Info("purged lapsed events", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// ParseRetention parses a duration like time.ParseDuration, with the addition
+// of a "d" (day) unit so operators can write "--event-retention=90d".
+func ParseRetention(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid day count: %w", err)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// buildWhere renders the WHERE clause shared by every Store implementation.
+// next is called once per placeholder and must return that placeholder's SQL
+// text, letting callers use either positional ("?") or numbered ("$1") params.
+func buildWhere(f *Filter, next func() string) (string, []any) {
+	var (
+		clauses []string
+		args    []any
+	)
+	if f.PersonID != "" {
+		clauses = append(clauses, "person_id = "+next())
+		args = append(args, f.PersonID)
+	}
+	if f.FobID != 0 {
+		clauses = append(clauses, "fob_id = "+next())
+		args = append(args, f.FobID)
+	}
+	if f.Since != 0 {
+		clauses = append(clauses, "timestamp >= "+next())
+		args = append(args, f.Since)
+	}
+	if f.Until != 0 {
+		clauses = append(clauses, "timestamp <= "+next())
+		args = append(args, f.Until)
+	}
+	if f.Authorized != nil {
+		clauses = append(clauses, "authorized = "+next())
+		args = append(args, *f.Authorized)
+	}
+	if len(clauses) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(clauses, " AND "), args
+}
+
+func queryLimit(f *Filter) int {
+	if f.Limit <= 0 || f.Limit > 1000 {
+		return 1000
+	}
+	return f.Limit
+}
+
+func scanEvents(rows *sql.Rows) ([]*Event, error) {
+	all := []*Event{}
+	for rows.Next() {
+		e := &Event{}
+		if err := rows.Scan(&e.Timestamp, &e.PersonID, &e.FobID, &e.QRID, &e.Authorized); err != nil {
+			return nil, err
+		}
+		all = append(all, e)
+	}
+	return all, rows.Err()
+}
@@ -0,0 +1,75 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS events (
+	timestamp  BIGINT NOT NULL,
+	person_id  TEXT NOT NULL,
+	fob_id     BIGINT NOT NULL,
+	qr_id      BIGINT NOT NULL,
+	authorized BOOLEAN NOT NULL
+);
+CREATE INDEX IF NOT EXISTS events_timestamp_person_fob_idx ON events (timestamp, person_id, fob_id);
+`
+
+// Postgres is a Store backed by a Postgres database, for deployments that already run one.
+type Postgres struct {
+	db *sql.DB
+}
+
+// NewPostgres opens (and migrates) a Postgres-backed Store using connStr, a
+// standard libpq connection string.
+func NewPostgres(connStr string) (*Postgres, error) {
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	if _, err := db.Exec(postgresSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("running migrations: %w", err)
+	}
+	return &Postgres{db: db}, nil
+}
+
+func (s *Postgres) Append(ctx context.Context, e *Event) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO events (timestamp, person_id, fob_id, qr_id, authorized) VALUES ($1, $2, $3, $4, $5)`,
+		e.Timestamp, e.PersonID, e.FobID, e.QRID, e.Authorized)
+	return err
+}
+
+func (s *Postgres) Query(ctx context.Context, f *Filter) ([]*Event, error) {
+	n := 0
+	next := func() string { n++; return fmt.Sprintf("$%d", n) }
+
+	where, args := buildWhere(f, next)
+	args = append(args, queryLimit(f), f.Offset)
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT timestamp, person_id, fob_id, qr_id, authorized FROM events %s ORDER BY timestamp DESC LIMIT %s OFFSET %s`,
+		where, next(), next(),
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanEvents(rows)
+}
+
+func (s *Postgres) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM events WHERE timestamp < $1`, cutoff.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *Postgres) Close() error { return s.db.Close() }
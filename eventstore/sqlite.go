@@ -0,0 +1,71 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS events (
+	timestamp  INTEGER NOT NULL,
+	person_id  TEXT NOT NULL,
+	fob_id     INTEGER NOT NULL,
+	qr_id      INTEGER NOT NULL,
+	authorized INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS events_timestamp_person_fob_idx ON events (timestamp, person_id, fob_id);
+`
+
+// SQLite is the default Store, backed by a single on-disk SQLite file.
+type SQLite struct {
+	db *sql.DB
+}
+
+// NewSQLite opens (and migrates) a SQLite-backed Store at path.
+func NewSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	db.SetMaxOpenConns(1) // the driver doesn't support concurrent writers
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("running migrations: %w", err)
+	}
+	return &SQLite{db: db}, nil
+}
+
+func (s *SQLite) Append(ctx context.Context, e *Event) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO events (timestamp, person_id, fob_id, qr_id, authorized) VALUES (?, ?, ?, ?, ?)`,
+		e.Timestamp, e.PersonID, e.FobID, e.QRID, e.Authorized)
+	return err
+}
+
+func (s *SQLite) Query(ctx context.Context, f *Filter) ([]*Event, error) {
+	where, args := buildWhere(f, func() string { return "?" })
+	args = append(args, queryLimit(f), f.Offset)
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT timestamp, person_id, fob_id, qr_id, authorized FROM events %s ORDER BY timestamp DESC LIMIT ? OFFSET ?`, where,
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanEvents(rows)
+}
+
+func (s *SQLite) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM events WHERE timestamp < ?`, cutoff.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *SQLite) Close() error { return s.db.Close() }
@@ -0,0 +1,138 @@
+package eventstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestSQLite(t *testing.T) *SQLite {
+	t.Helper()
+	s, err := NewSQLite(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLiteAppendAndQuery(t *testing.T) {
+	s := newTestSQLite(t)
+	ctx := context.Background()
+
+	authorized := true
+	denied := false
+	events := []*Event{
+		{Timestamp: 1000, PersonID: "alice", FobID: 1, QRID: 0, Authorized: authorized},
+		{Timestamp: 2000, PersonID: "bob", FobID: 2, QRID: 0, Authorized: denied},
+		{Timestamp: 3000, PersonID: "alice", FobID: 1, QRID: 0, Authorized: authorized},
+	}
+	for _, e := range events {
+		if err := s.Append(ctx, e); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	got, err := s.Query(ctx, &Filter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(got))
+	}
+	// Most recent first.
+	if got[0].Timestamp != 3000 || got[2].Timestamp != 1000 {
+		t.Fatalf("expected descending timestamp order, got %+v", got)
+	}
+}
+
+func TestSQLiteQueryFilters(t *testing.T) {
+	s := newTestSQLite(t)
+	ctx := context.Background()
+
+	authorized := true
+	denied := false
+	for _, e := range []*Event{
+		{Timestamp: 1000, PersonID: "alice", FobID: 1, Authorized: authorized},
+		{Timestamp: 2000, PersonID: "bob", FobID: 2, Authorized: denied},
+		{Timestamp: 3000, PersonID: "alice", FobID: 1, Authorized: denied},
+	} {
+		if err := s.Append(ctx, e); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	t.Run("by person", func(t *testing.T) {
+		got, err := s.Query(ctx, &Filter{PersonID: "alice"})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("expected 2 events for alice, got %d", len(got))
+		}
+	})
+
+	t.Run("by authorized", func(t *testing.T) {
+		f := false
+		got, err := s.Query(ctx, &Filter{Authorized: &f})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("expected 2 denied events, got %d", len(got))
+		}
+	})
+
+	t.Run("by time range", func(t *testing.T) {
+		got, err := s.Query(ctx, &Filter{Since: 1500, Until: 2500})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		if len(got) != 1 || got[0].Timestamp != 2000 {
+			t.Fatalf("expected only the event at 2000, got %+v", got)
+		}
+	})
+
+	t.Run("limit and offset", func(t *testing.T) {
+		got, err := s.Query(ctx, &Filter{Limit: 1, Offset: 1})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		if len(got) != 1 || got[0].Timestamp != 2000 {
+			t.Fatalf("expected the second-most-recent event, got %+v", got)
+		}
+	})
+}
+
+func TestSQLitePurge(t *testing.T) {
+	s := newTestSQLite(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	recent := now.Add(-time.Hour)
+
+	if err := s.Append(ctx, &Event{Timestamp: old.UnixMilli(), PersonID: "alice", FobID: 1}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := s.Append(ctx, &Event{Timestamp: recent.UnixMilli(), PersonID: "bob", FobID: 2}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	n, err := s.Purge(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 row purged, got %d", n)
+	}
+
+	got, err := s.Query(ctx, &Filter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(got) != 1 || got[0].PersonID != "bob" {
+		t.Fatalf("expected only bob's event to remain, got %+v", got)
+	}
+}
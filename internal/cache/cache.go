@@ -0,0 +1,257 @@
+// Package cache implements a generic, refreshable single-value cache with
+// ETag-style change detection, background refresh, and resilience against a
+// flapping backing source.
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Filler produces the current value of a Cache. It's called on a timer and
+// whenever a refresh is requested; a flapping Filler is protected against by
+// Options.NegativeTTL and the exponential backoff applied by Run.
+type Filler[T any] func(ctx context.Context) (T, error)
+
+// Update is delivered to watchers whenever Fill or Set changes the cached value.
+type Update[T any] struct {
+	Value T
+	Hash  string
+}
+
+// Options configures a Cache's background behavior. Zero values disable the
+// corresponding feature.
+type Options struct {
+	// TTL expires the cached value if it isn't refreshed within this long,
+	// so Load stops returning stale data if the refresh loop has died.
+	TTL time.Duration
+
+	// NegativeTTL is how long a Filler error is remembered: Fill calls made
+	// within this window of a failure return the remembered error instead
+	// of invoking the Filler again.
+	NegativeTTL time.Duration
+
+	// ResyncInterval is the base interval Run fills on, jittered by ±10% so
+	// multiple replicas starting simultaneously don't resync in lockstep.
+	ResyncInterval time.Duration
+
+	// MaxBackoff caps the exponential backoff Run applies between retries
+	// after a Filler error. Defaults to ResyncInterval if zero.
+	MaxBackoff time.Duration
+}
+
+// Cache holds the most recently filled value of type T alongside a hash
+// computed by a user-supplied function, suitable for use as an HTTP ETag.
+type Cache[T any] struct {
+	filler Filler[T]
+	hash   func(T) string
+	opts   Options
+
+	lock     sync.Mutex
+	value    T
+	valSet   bool
+	valHash  string
+	expiry   *time.Timer
+	watchers map[chan Update[T]]struct{}
+
+	lastErr       error
+	negativeUntil time.Time
+}
+
+// New returns a Cache that calls filler to produce values and hash to
+// compute their ETag.
+func New[T any](filler Filler[T], hash func(T) string, opts Options) *Cache[T] {
+	return &Cache[T]{
+		filler:   filler,
+		hash:     hash,
+		opts:     opts,
+		watchers: map[chan Update[T]]struct{}{},
+	}
+}
+
+// Load returns the most recently filled value and its hash. The zero value
+// and an empty hash are returned before the first successful Fill, or after
+// the value has expired per Options.TTL.
+func (c *Cache[T]) Load() (T, string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.value, c.valHash
+}
+
+// Set overwrites the cached value directly, without going through the
+// Filler. Watchers are notified only if the hash actually changed.
+func (c *Cache[T]) Set(v T) {
+	hash := c.hash(v)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.valSet && c.valHash == hash {
+		return
+	}
+	c.setLocked(v, hash)
+}
+
+// Fill invokes the Filler and updates the cache if the result differs from
+// what's currently stored. If a previous call failed within Options.NegativeTTL,
+// the Filler is not invoked again and the remembered error is returned.
+func (c *Cache[T]) Fill(ctx context.Context) error {
+	c.lock.Lock()
+	if !c.negativeUntil.IsZero() && time.Now().Before(c.negativeUntil) {
+		err := c.lastErr
+		c.lock.Unlock()
+		return err
+	}
+	c.lock.Unlock()
+
+	v, err := c.filler(ctx)
+	if err != nil {
+		c.lock.Lock()
+		c.lastErr = err
+		if c.opts.NegativeTTL > 0 {
+			c.negativeUntil = time.Now().Add(c.opts.NegativeTTL)
+		}
+		c.lock.Unlock()
+		return err
+	}
+	hash := c.hash(v)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lastErr = nil
+	c.negativeUntil = time.Time{}
+
+	if c.valSet && c.valHash == hash {
+		c.touchExpiryLocked() // refresh succeeded; keep TTL tracking "still being refreshed", not "last changed"
+		return nil
+	}
+	c.setLocked(v, hash)
+	return nil
+}
+
+// setLocked stores v/hash, resets the expiry timer, and notifies watchers. c.lock must be held.
+func (c *Cache[T]) setLocked(v T, hash string) {
+	c.value = v
+	c.valHash = hash
+	c.valSet = true
+
+	if c.opts.TTL > 0 {
+		if c.expiry != nil {
+			c.expiry.Stop()
+		}
+		c.expiry = time.AfterFunc(c.opts.TTL, c.expireLocked)
+	}
+
+	update := Update[T]{Value: v, Hash: hash}
+	for ch := range c.watchers {
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
+
+// touchExpiryLocked re-arms the expiry timer without changing the cached
+// value or notifying watchers. c.lock must be held.
+func (c *Cache[T]) touchExpiryLocked() {
+	if c.opts.TTL <= 0 {
+		return
+	}
+	if c.expiry != nil {
+		c.expiry.Stop()
+	}
+	c.expiry = time.AfterFunc(c.opts.TTL, c.expireLocked)
+}
+
+func (c *Cache[T]) expireLocked() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var zero T
+	c.value = zero
+	c.valHash = ""
+	c.valSet = false
+}
+
+// Wait blocks until the cache changes or period elapses, whichever comes first.
+func (c *Cache[T]) Wait(period time.Duration) {
+	ch, cancel := c.Subscribe()
+	defer cancel()
+
+	t := time.NewTimer(period)
+	defer t.Stop()
+
+	select {
+	case <-ch:
+	case <-t.C:
+	}
+}
+
+// Subscribe registers a watcher that receives an Update every time Fill or
+// Set changes the cached value. Callers must invoke the returned cancel func
+// when done with the channel.
+func (c *Cache[T]) Subscribe() (<-chan Update[T], func()) {
+	ch := make(chan Update[T], 1)
+	c.lock.Lock()
+	c.watchers[ch] = struct{}{}
+	c.lock.Unlock()
+
+	cancel := func() {
+		c.lock.Lock()
+		delete(c.watchers, ch)
+		c.lock.Unlock()
+	}
+	return ch, cancel
+}
+
+// Run fills the cache on a jittered ResyncInterval timer, immediately on
+// every signal received from refresh, and retries with exponential backoff
+// (capped at MaxBackoff) after Filler errors. It blocks until ctx is canceled.
+func (c *Cache[T]) Run(ctx context.Context, refresh <-chan struct{}) {
+	maxBackoff := c.opts.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = c.opts.ResyncInterval
+	}
+
+	t := time.NewTimer(jitter(c.opts.ResyncInterval))
+	defer t.Stop()
+
+	var backoff time.Duration
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-refresh:
+		case <-t.C:
+		}
+
+		if err := c.Fill(ctx); err != nil {
+			slog.Error("unable to fill cache", "error", err)
+
+			if backoff == 0 {
+				backoff = time.Millisecond * 250
+			} else {
+				backoff += backoff / 2
+			}
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			t.Reset(backoff)
+			continue
+		}
+
+		backoff = 0
+		t.Reset(jitter(c.opts.ResyncInterval))
+	}
+}
+
+// jitter returns d adjusted by a random amount within ±10%, or d unchanged if d <= 0.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return d
+	}
+	spread := float64(d) * 0.1
+	offset := (rand.Float64()*2 - 1) * spread
+	return d + time.Duration(offset)
+}
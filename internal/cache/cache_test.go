@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestFillResetsTTLOnUnchangedValue guards against a regression where a
+// healthy Filler returning unchanged data would still let the entry expire,
+// because only setLocked (called on a hash change) reset the expiry timer.
+func TestFillResetsTTLOnUnchangedValue(t *testing.T) {
+	c := New(func(ctx context.Context) (string, error) {
+		return "same", nil
+	}, func(v string) string { return v }, Options{TTL: 30 * time.Millisecond})
+
+	if err := c.Fill(context.Background()); err != nil {
+		t.Fatalf("initial Fill: %v", err)
+	}
+
+	deadline := time.Now().Add(100 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if err := c.Fill(context.Background()); err != nil {
+			t.Fatalf("Fill: %v", err)
+		}
+		if v, _ := c.Load(); v != "same" {
+			t.Fatalf("value expired despite successful refills within TTL, got %q", v)
+		}
+	}
+}
+
+func TestFillExpiresAfterRefreshesStop(t *testing.T) {
+	c := New(func(ctx context.Context) (string, error) {
+		return "same", nil
+	}, func(v string) string { return v }, Options{TTL: 20 * time.Millisecond})
+
+	if err := c.Fill(context.Background()); err != nil {
+		t.Fatalf("Fill: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if v, hash := c.Load(); v != "" || hash != "" {
+		t.Fatalf("expected expired zero value, got %q/%q", v, hash)
+	}
+}
@@ -2,36 +2,116 @@ package main
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/TheLab.ms/fobsvr/access"
+	"github.com/TheLab.ms/fobsvr/eventstore"
+	"github.com/TheLab.ms/fobsvr/internal/cache"
+	"github.com/TheLab.ms/fobsvr/sources/file"
+	"github.com/TheLab.ms/fobsvr/sources/keycloak"
+	"github.com/TheLab.ms/fobsvr/sources/scim"
 	"github.com/julienschmidt/httprouter"
 )
 
 func main() {
 	var (
-		callbackURL     = flag.String("callback-url", "", "URL at which Keycloak can reach this service")
-		resync          = flag.Duration("resync-interval", time.Hour, "How often to resync if no webhook has been received")
-		keycloakURL     = flag.String("keycloak-url", "", "Base URL of Keycloak")
-		keycloakGroupID = flag.String("keycloak-group-id", "", "UUID of the trusted Keycloak group")
+		callbackURL      = flag.String("callback-url", "", "URL at which Keycloak can reach this service")
+		resync           = flag.Duration("resync-interval", time.Hour, "How often to resync if no push-based refresh has been received")
+		sourceName       = flag.String("source", "keycloak", "Identity source backing the fob cache (keycloak, scim, file)")
+		keycloakURL      = flag.String("keycloak-url", "", "Base URL of Keycloak")
+		scimURL          = flag.String("scim-url", "", "Base URL of the SCIM 2.0 server")
+		scimToken        = flag.String("scim-token", "", "Bearer token used to authenticate against the SCIM server")
+		fileSourcePath   = flag.String("file-source-path", "", "Path to a YAML file of static users, for the file source")
+		eventStoreDriver = flag.String("event-store-driver", "sqlite", "Driver used to persist access events (sqlite, postgres)")
+		eventStoreDSN    = flag.String("event-store-dsn", "fobsvr-events.db", "Connection string (or file path, for sqlite) of the event store")
+		eventRetention   = flag.String("event-retention", "90d", "How long to keep access events before purging them, e.g. 90d, 720h")
+		keycloakGroups   keycloakGroupFlags
+		scimGroups       scimGroupFlags
 	)
+	flag.Var(&keycloakGroups, "keycloak-group", "Trusted Keycloak group, formatted as <group UUID>=<default TTL> (repeatable)")
+	flag.Var(&scimGroups, "scim-group", "Trusted SCIM group, formatted as <group id>=<default TTL> (repeatable)")
 	flag.Parse()
 
-	k := newKeycloak(*keycloakURL, *keycloakGroupID)
-	if *callbackURL != "" {
-		err := k.EnsureWebhook(context.Background(), *callbackURL)
-		if err != nil {
+	retention, err := eventstore.ParseRetention(*eventRetention)
+	if err != nil {
+		panic(err)
+	}
+
+	var store eventstore.Store
+	switch *eventStoreDriver {
+	case "sqlite":
+		store, err = eventstore.NewSQLite(*eventStoreDSN)
+	case "postgres":
+		store, err = eventstore.NewPostgres(*eventStoreDSN)
+	default:
+		panic("unknown --event-store-driver: " + *eventStoreDriver)
+	}
+	if err != nil {
+		panic(err)
+	}
+	defer store.Close()
+	go eventstore.RunRetention(context.Background(), store, retention)
+
+	var (
+		source   access.Source
+		kcSource *keycloak.Keycloak
+	)
+	switch *sourceName {
+	case "keycloak":
+		if len(keycloakGroups) == 0 {
+			panic("at least one --keycloak-group is required")
+		}
+		kcSource = keycloak.New(*keycloakURL, keycloakGroups)
+		source = kcSource
+	case "scim":
+		if len(scimGroups) == 0 {
+			panic("at least one --scim-group is required")
+		}
+		source = scim.New(*scimURL, *scimToken, scimGroups)
+	case "file":
+		if *fileSourcePath == "" {
+			panic("--file-source-path is required when --source=file")
+		}
+		source = file.New(*fileSourcePath)
+	default:
+		panic("unknown --source: " + *sourceName)
+	}
+
+	if kcSource != nil && *callbackURL != "" {
+		if err := kcSource.EnsureWebhook(context.Background(), *callbackURL); err != nil {
 			panic(err)
 		}
 	}
 
+	fobsCache := cache.New(func(ctx context.Context) ([]*access.AccessUser, error) {
+		users, err := source.ListUsers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(users, func(i, j int) bool { return users[i].FobID < users[j].FobID })
+		return users, nil
+	}, calculateUsersHash, cache.Options{
+		ResyncInterval: *resync,
+		NegativeTTL:    time.Millisecond * 250,
+		MaxBackoff:     *resync,
+	})
+
 	router := httprouter.New()
-	cache := newCache(k)
 	router.GET("/healthz", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-		if c, _ := cache.Load(); c == nil {
+		if c, _ := fobsCache.Load(); c == nil {
 			w.WriteHeader(500) // wait for cache to warm before accepting requests
 			return
 		}
@@ -45,10 +125,10 @@ func main() {
 				http.Error(w, err.Error(), 400)
 				return
 			}
-			cache.Wait(waitDuration)
+			fobsCache.Wait(waitDuration)
 		}
 
-		users, hash := cache.Load()
+		users, hash := fobsCache.Load()
 		if hash != "" && hash == r.Header.Get("If-None-Match") {
 			w.WriteHeader(304)
 			return
@@ -59,21 +139,158 @@ func main() {
 		json.NewEncoder(w).Encode(&users)
 	})
 
+	router.GET("/v1/fobs/stream", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(200)
+
+		prev, hash := fobsCache.Load()
+		if err := writeSSEEvent(w, "snapshot", &fobsSnapshot{Users: prev, Hash: hash}); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		updates, cancel := fobsCache.Subscribe()
+		defer cancel()
+
+		heartbeat := time.NewTicker(30 * time.Second)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+
+			case <-heartbeat.C:
+				if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+
+			case update := <-updates:
+				diff := diffUsers(prev, update.Value)
+				if err := writeSSEEvent(w, "update", &fobsUpdate{Hash: update.Hash, fobsDiff: diff}); err != nil {
+					return
+				}
+				flusher.Flush()
+				prev = update.Value
+			}
+		}
+	})
+
 	router.POST("/v1/events", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-		e := &Event{}
+		e := &eventstore.Event{}
 		err := json.NewDecoder(r.Body).Decode(e)
 		if err != nil {
 			http.Error(w, err.Error(), 400)
 			return
 		}
 
-		// TODO: Actually store these somewhere
-		slog.Info("received event", "timestamp", e.Timestamp, "personID", e.PersonID, "fobID", e.FobID, "qrID", e.QRID, "authorized", e.Authorized)
+		if err := store.Append(r.Context(), e); err != nil {
+			slog.Error("unable to store event", "error", err)
+			http.Error(w, "unable to store event", 500)
+			return
+		}
+		w.WriteHeader(204)
+	})
+
+	router.GET("/v1/events", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		var err error
+		q := r.URL.Query()
+		f := &eventstore.Filter{PersonID: q.Get("personID")}
+
+		if fobID := q.Get("fobID"); fobID != "" {
+			f.FobID, err = strconv.ParseInt(fobID, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid fobID: "+err.Error(), 400)
+				return
+			}
+		}
+		if since := q.Get("since"); since != "" {
+			f.Since, err = strconv.ParseInt(since, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid since: "+err.Error(), 400)
+				return
+			}
+		}
+		if until := q.Get("until"); until != "" {
+			f.Until, err = strconv.ParseInt(until, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid until: "+err.Error(), 400)
+				return
+			}
+		}
+		if authorized := q.Get("authorized"); authorized != "" {
+			b, err := strconv.ParseBool(authorized)
+			if err != nil {
+				http.Error(w, "invalid authorized: "+err.Error(), 400)
+				return
+			}
+			f.Authorized = &b
+		}
+		if limit := q.Get("limit"); limit != "" {
+			f.Limit, err = strconv.Atoi(limit)
+			if err != nil {
+				http.Error(w, "invalid limit: "+err.Error(), 400)
+				return
+			}
+		}
+		if offset := q.Get("offset"); offset != "" {
+			f.Offset, err = strconv.Atoi(offset)
+			if err != nil {
+				http.Error(w, "invalid offset: "+err.Error(), 400)
+				return
+			}
+		}
+
+		events, err := store.Query(r.Context(), f)
+		if err != nil {
+			slog.Error("unable to query events", "error", err)
+			http.Error(w, "unable to query events", 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&eventsPage{Events: events, Limit: f.Limit, Offset: f.Offset})
 	})
 
 	refresh := make(chan struct{}, 1)
 	refresh <- struct{}{}
+
+	if s, ok := source.(access.Subscribable); ok {
+		pushed, err := s.Subscribe(context.Background())
+		if err != nil {
+			panic(err)
+		}
+		go func() {
+			for range pushed {
+				select {
+				case refresh <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+
 	router.POST("/webhook", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+
+		if !verifyWebhookSignature(body, r.Header.Get("X-Keycloak-Signature")) {
+			http.Error(w, "invalid signature", 401)
+			return
+		}
+
 		slog.Info("received webhook")
 		select {
 		case refresh <- struct{}{}:
@@ -81,46 +298,100 @@ func main() {
 		}
 	})
 
-	// Sync periodically
-	go func() {
-		for range time.NewTicker(*resync).C {
-			select {
-			case refresh <- struct{}{}:
-			default:
-			}
-		}
-	}()
+	go fobsCache.Run(context.Background(), refresh)
 
-	// Keycloak loop
-	go func() {
-		var lastRetry time.Duration
-		for range refresh {
-			err := cache.Fill()
-			if err != nil {
-				slog.Error("unable to fill cache", "error", err)
-			} else {
-				lastRetry = 0
-				continue
-			}
+	panic(http.ListenAndServe(":8080", router))
+}
 
-			if lastRetry == 0 {
-				lastRetry = time.Millisecond * 250
-			}
-			lastRetry += lastRetry / 2
-			if lastRetry > *resync {
-				lastRetry = *resync
-			}
-			time.Sleep(lastRetry)
-		}
-	}()
+// eventsPage is the paginated response body for GET /v1/events.
+type eventsPage struct {
+	Events []*eventstore.Event `json:"events"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+}
 
-	panic(http.ListenAndServe(":8080", router))
+// fobsSnapshot is the initial "snapshot" event sent over GET /v1/fobs/stream.
+type fobsSnapshot struct {
+	Users []*access.AccessUser `json:"users"`
+	Hash  string               `json:"hash"`
+}
+
+// fobsUpdate is the "update" event sent over GET /v1/fobs/stream whenever the cache changes.
+type fobsUpdate struct {
+	Hash string `json:"hash"`
+	*fobsDiff
+}
+
+func writeSSEEvent(w http.ResponseWriter, event string, data any) error {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, js)
+	return err
+}
+
+// keycloakGroupFlags collects repeated -keycloak-group flags into []keycloak.GroupConfig.
+type keycloakGroupFlags []keycloak.GroupConfig
+
+func (g *keycloakGroupFlags) String() string {
+	return fmt.Sprint([]keycloak.GroupConfig(*g))
+}
+
+func (g *keycloakGroupFlags) Set(value string) error {
+	id, ttl, err := parseGroupFlag(value)
+	if err != nil {
+		return err
+	}
+	*g = append(*g, keycloak.GroupConfig{ID: id, DefaultTTL: ttl})
+	return nil
 }
 
-type Event struct {
-	Timestamp  int64  `json:"timestamp"`
-	PersonID   string `json:"personID"`
-	FobID      int64  `json:"fobID"`
-	QRID       int64  `json:"qrID"`
-	Authorized bool   `json:"authorized"`
+// scimGroupFlags collects repeated -scim-group flags into []scim.GroupConfig.
+type scimGroupFlags []scim.GroupConfig
+
+func (g *scimGroupFlags) String() string {
+	return fmt.Sprint([]scim.GroupConfig(*g))
+}
+
+func (g *scimGroupFlags) Set(value string) error {
+	id, ttl, err := parseGroupFlag(value)
+	if err != nil {
+		return err
+	}
+	*g = append(*g, scim.GroupConfig{ID: id, DefaultTTL: ttl})
+	return nil
+}
+
+func parseGroupFlag(value string) (id string, ttl time.Duration, err error) {
+	id, rawTTL, ok := strings.Cut(value, "=")
+	if !ok {
+		return "", 0, fmt.Errorf("expected format <group id>=<default TTL>, got %q", value)
+	}
+
+	ttl, err = time.ParseDuration(rawTTL)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid default TTL: %w", err)
+	}
+	return id, ttl, nil
+}
+
+// verifyWebhookSignature reports whether sig is a valid hex-encoded HMAC-SHA256
+// of body, keyed by the shared secret Keycloak was configured with.
+func verifyWebhookSignature(body []byte, sig string) bool {
+	secret, err := os.ReadFile("/var/lib/keycloak/webhook-secret")
+	if err != nil {
+		slog.Error("unable to read webhook secret", "error", err)
+		return false
+	}
+	return verifyHMACSignature(body, sig, secret)
+}
+
+// verifyHMACSignature reports whether sig is a valid hex-encoded HMAC-SHA256 of body, keyed by secret.
+func verifyHMACSignature(body []byte, sig string, secret []byte) bool {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(sig))
 }
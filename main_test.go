@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/TheLab.ms/fobsvr/access"
+)
+
+func TestDiffUsers(t *testing.T) {
+	alice := &access.AccessUser{UserID: "alice", FobID: 1, TTL: 1000}
+	bob := &access.AccessUser{UserID: "bob", FobID: 2, TTL: 1000}
+	bobChanged := &access.AccessUser{UserID: "bob", FobID: 2, TTL: 2000}
+	carol := &access.AccessUser{UserID: "carol", FobID: 3, TTL: 1000}
+
+	diff := diffUsers([]*access.AccessUser{alice, bob}, []*access.AccessUser{bobChanged, carol})
+
+	if len(diff.Added) != 1 || diff.Added[0].UserID != "carol" {
+		t.Fatalf("expected carol added, got %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].UserID != "alice" {
+		t.Fatalf("expected alice removed, got %+v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].UserID != "bob" {
+		t.Fatalf("expected bob changed, got %+v", diff.Changed)
+	}
+}
+
+func TestDiffUsersNoChanges(t *testing.T) {
+	alice := &access.AccessUser{UserID: "alice", FobID: 1, TTL: 1000}
+	diff := diffUsers([]*access.AccessUser{alice}, []*access.AccessUser{alice})
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed) != 0 {
+		t.Fatalf("expected no diff, got %+v", diff)
+	}
+}
+
+func TestVerifyHMACSignature(t *testing.T) {
+	secret := []byte("shared-secret")
+	body := []byte(`{"type":"access.grant"}`)
+	sig := sign(body, secret)
+
+	if !verifyHMACSignature(body, sig, secret) {
+		t.Fatal("expected valid signature to verify")
+	}
+	if verifyHMACSignature(body, sig, []byte("wrong-secret")) {
+		t.Fatal("expected signature keyed by a different secret to fail")
+	}
+	if verifyHMACSignature([]byte("tampered"), sig, secret) {
+		t.Fatal("expected signature over a different body to fail")
+	}
+}
+
+func sign(body, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,76 @@
+// Package file adapts a static YAML user list into an access.Source, for
+// air-gapped deployments and tests where no real identity provider is available.
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/TheLab.ms/fobsvr/access"
+)
+
+// Source reads its user list from a YAML file on every ListUsers call, so
+// edits take effect on the next resync without restarting the service.
+type Source struct {
+	path string
+}
+
+// New returns a file-backed access.Source reading from path.
+func New(path string) *Source {
+	return &Source{path: path}
+}
+
+// entry is the YAML representation of a single user.
+type entry struct {
+	UserID   string `yaml:"userID"`
+	FobID    int    `yaml:"fobID"`
+	QRID     int    `yaml:"qrID"`
+	TTL      string `yaml:"ttl"`
+	Schedule string `yaml:"schedule"`
+}
+
+func (s *Source) ListUsers(ctx context.Context) ([]*access.AccessUser, error) {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", s.path, err)
+	}
+
+	var entries []entry
+	if err := yaml.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", s.path, err)
+	}
+
+	all := make([]*access.AccessUser, 0, len(entries))
+	for _, e := range entries {
+		ttl := 24 * time.Hour
+		if e.TTL != "" {
+			d, err := time.ParseDuration(e.TTL)
+			if err != nil {
+				return nil, fmt.Errorf("user %s: invalid ttl %q: %w", e.UserID, e.TTL, err)
+			}
+			ttl = d
+		}
+
+		var schedule *access.Schedule
+		if e.Schedule != "" {
+			sched, err := access.ParseSchedule(e.Schedule)
+			if err != nil {
+				return nil, fmt.Errorf("user %s: invalid schedule %q: %w", e.UserID, e.Schedule, err)
+			}
+			schedule = sched
+		}
+
+		all = append(all, &access.AccessUser{
+			UserID:   e.UserID,
+			FobID:    e.FobID,
+			QRID:     e.QRID,
+			TTL:      ttl.Milliseconds(),
+			Schedule: schedule,
+		})
+	}
+	return all, nil
+}
@@ -0,0 +1,68 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListUsers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.yaml")
+	err := os.WriteFile(path, []byte(`
+- userID: alice
+  fobID: 1
+  ttl: 24h
+- userID: bob
+  fobID: 2
+  ttl: 1h
+  schedule: "Mon-Fri 08:00-18:00"
+`), 0o644)
+	if err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	users, err := New(path).ListUsers(context.Background())
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(users))
+	}
+
+	if users[1].Schedule == nil || users[1].Schedule.Start != "08:00" {
+		t.Fatalf("expected bob's schedule to be parsed, got %+v", users[1].Schedule)
+	}
+
+	// Edits to the file take effect on the next call, since it's re-read each time.
+	err = os.WriteFile(path, []byte(`
+- userID: alice
+  fobID: 1
+`), 0o644)
+	if err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	users, err = New(path).ListUsers(context.Background())
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if len(users) != 1 {
+		t.Fatalf("expected re-read to reflect edit, got %d users", len(users))
+	}
+}
+
+func TestListUsersInvalidTTL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.yaml")
+	err := os.WriteFile(path, []byte(`
+- userID: alice
+  fobID: 1
+  ttl: not-a-duration
+`), 0o644)
+	if err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if _, err := New(path).ListUsers(context.Background()); err == nil {
+		t.Fatal("expected error for invalid ttl")
+	}
+}
@@ -0,0 +1,240 @@
+// Package keycloak adapts a Keycloak realm into an access.Source.
+package keycloak
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Nerzal/gocloak/v13"
+
+	"github.com/TheLab.ms/fobsvr/access"
+)
+
+// GroupConfig describes one Keycloak group that grants building access,
+// along with the TTL handed out to its members by default.
+type GroupConfig struct {
+	ID         string
+	DefaultTTL time.Duration
+}
+
+// Keycloak adapts a Keycloak realm's group membership into access.AccessUser records.
+type Keycloak struct {
+	client         *gocloak.GoCloak
+	realm, baseURL string
+	groups         []GroupConfig
+
+	// use ensureToken to access these
+	tokenLock      sync.Mutex
+	token          *gocloak.JWT
+	tokenFetchTime time.Time
+}
+
+// New returns a Keycloak-backed access.Source that pulls membership from groups.
+func New(url string, groups []GroupConfig) *Keycloak {
+	return &Keycloak{client: gocloak.NewClient(url), realm: "master", baseURL: url, groups: groups}
+}
+
+func (k *Keycloak) ListUsers(ctx context.Context) ([]*access.AccessUser, error) {
+	token, err := k.ensureToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting token: %w", err)
+	}
+
+	byID := map[string]*access.AccessUser{}
+	for _, group := range k.groups {
+		var (
+			max   = 50
+			first = 0
+		)
+		for {
+			params, err := gocloak.GetQueryParams(gocloak.GetUsersParams{
+				Max:   &max,
+				First: &first,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			// Unfortunately the keycloak client doesn't support the group membership endpoint.
+			// We reuse the client's transport here while specifying our own URL.
+			var users []*gocloak.User
+			_, err = k.client.GetRequestWithBearerAuth(ctx, token.AccessToken).
+				SetResult(&users).
+				SetQueryParams(params).
+				Get(fmt.Sprintf("%s/admin/realms/%s/groups/%s/members", k.baseURL, k.realm, group.ID))
+			if err != nil {
+				return nil, err
+			}
+			if len(users) == 0 {
+				break
+			}
+			first += len(users)
+
+			for _, user := range users {
+				u := newAccessUser(user, group.DefaultTTL)
+				if u == nil {
+					continue // invalid user (should be impossible)
+				}
+				byID[u.UserID] = u // a user present in multiple trusted groups takes the last group's settings
+			}
+		}
+	}
+
+	all := make([]*access.AccessUser, 0, len(byID))
+	for _, u := range byID {
+		all = append(all, u)
+	}
+	return all, nil
+}
+
+func (k *Keycloak) EnsureWebhook(ctx context.Context, callbackURL string) error {
+	secret, err := os.ReadFile("/var/lib/keycloak/webhook-secret")
+	if err != nil {
+		return fmt.Errorf("reading webhook secret: %w", err)
+	}
+
+	hooks, err := k.ListWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("listing: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/webhook", callbackURL)
+	for _, hook := range hooks {
+		if hook.URL == url {
+			return nil // already exists
+		}
+	}
+
+	return k.CreateWebhook(ctx, &Webhook{
+		Enabled:    true,
+		URL:        url,
+		EventTypes: []string{"admin.*"},
+		Secret:     string(secret),
+	})
+}
+
+func (k *Keycloak) ListWebhooks(ctx context.Context) ([]*Webhook, error) {
+	token, err := k.ensureToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting token: %w", err)
+	}
+
+	webhooks := []*Webhook{}
+	_, err = k.client.GetRequestWithBearerAuth(ctx, token.AccessToken).
+		SetResult(&webhooks).
+		Get(fmt.Sprintf("%s/realms/%s/webhooks", k.baseURL, k.realm))
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (k *Keycloak) CreateWebhook(ctx context.Context, webhook *Webhook) error {
+	token, err := k.ensureToken(ctx)
+	if err != nil {
+		return fmt.Errorf("getting token: %w", err)
+	}
+
+	_, err = k.client.GetRequestWithBearerAuth(ctx, token.AccessToken).
+		SetBody(webhook).
+		Post(fmt.Sprintf("%s/realms/%s/webhooks", k.baseURL, k.realm))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// For whatever reason the Keycloak client doesn't support token rotation
+func (k *Keycloak) ensureToken(ctx context.Context) (*gocloak.JWT, error) {
+	k.tokenLock.Lock()
+	defer k.tokenLock.Unlock()
+
+	if k.token != nil && time.Since(k.tokenFetchTime) < (time.Duration(k.token.ExpiresIn)*time.Second)/2 {
+		return k.token, nil
+	}
+
+	clientID, err := os.ReadFile("/var/lib/keycloak/client-id")
+	if err != nil {
+		return nil, fmt.Errorf("reading client id: %w", err)
+	}
+	clientSecret, err := os.ReadFile("/var/lib/keycloak/client-secret")
+	if err != nil {
+		return nil, fmt.Errorf("reading client secret: %w", err)
+	}
+
+	token, err := k.client.LoginClient(ctx, string(clientID), string(clientSecret), k.realm)
+	if err != nil {
+		return nil, err
+	}
+	k.token = token
+	k.tokenFetchTime = time.Now()
+
+	log.Printf("fetched new auth token from keycloak - will expire in %d seconds", k.token.ExpiresIn)
+	return k.token, nil
+}
+
+func newAccessUser(kcuser *gocloak.User, defaultTTL time.Duration) *access.AccessUser {
+	if kcuser.ID == nil || kcuser.Attributes == nil {
+		return nil
+	}
+
+	attr := *kcuser.Attributes
+	fobID, _ := strconv.Atoi(firstElOrZeroVal(attr["keyfobID"]))
+	qrID, _ := strconv.Atoi(firstElOrZeroVal(attr["qrID"]))
+	if fobID == 0 && qrID == 0 {
+		return nil
+	}
+	if firstElOrZeroVal(attr["buildingAccessApprover"]) == "" {
+		return nil // no access for accounts that haven't explicitly been granted building access
+	}
+
+	ttl := defaultTTL
+	if raw := firstElOrZeroVal(attr["fobTTL"]); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil {
+			ttl = d
+		} else {
+			slog.Warn("ignoring invalid fobTTL attribute", "userID", *kcuser.ID, "value", raw, "error", err)
+		}
+	}
+
+	var schedule *access.Schedule
+	if raw := firstElOrZeroVal(attr["fobSchedule"]); raw != "" {
+		s, err := access.ParseSchedule(raw)
+		if err != nil {
+			slog.Warn("ignoring invalid fobSchedule attribute", "userID", *kcuser.ID, "value", raw, "error", err)
+		} else {
+			schedule = s
+		}
+	}
+
+	return &access.AccessUser{
+		UserID:   *kcuser.ID,
+		FobID:    fobID,
+		QRID:     qrID,
+		TTL:      ttl.Milliseconds(),
+		Schedule: schedule,
+	}
+}
+
+type Webhook struct {
+	ID         string   `json:"id"`
+	Enabled    bool     `json:"enabled"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+	Secret     string   `json:"secret,omitempty"`
+}
+
+func firstElOrZeroVal[T any](slice []T) (val T) {
+	if len(slice) == 0 {
+		return val
+	}
+	return slice[0]
+}
@@ -0,0 +1,88 @@
+package scim
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TheLab.ms/fobsvr/access"
+)
+
+func TestListUsers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/Groups/engineers", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"members":[{"value":"alice"},{"value":"bob"},{"value":"carol"}]}`))
+	})
+	mux.HandleFunc("/Users/alice", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"id": "alice",
+			"urn:thelab:params:scim:schemas:extension:fobsvr:1.0:User": {
+				"keyfobID": "1234",
+				"buildingAccessApprover": "jdoe"
+			}
+		}`))
+	})
+	mux.HandleFunc("/Users/bob", func(w http.ResponseWriter, r *http.Request) {
+		// bob has a typo'd fobTTL: this must not fail the whole sync, just fall back to the default.
+		w.Write([]byte(`{
+			"id": "bob",
+			"urn:thelab:params:scim:schemas:extension:fobsvr:1.0:User": {
+				"keyfobID": "5678",
+				"buildingAccessApprover": "jdoe",
+				"fobTTL": "not-a-duration"
+			}
+		}`))
+	})
+	mux.HandleFunc("/Users/carol", func(w http.ResponseWriter, r *http.Request) {
+		// carol hasn't been granted access, so she's excluded entirely.
+		w.Write([]byte(`{"id": "carol"}`))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	src := New(srv.URL, "", []GroupConfig{{ID: "engineers", DefaultTTL: 24 * time.Hour}})
+	users, err := src.ListUsers(context.Background())
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users, got %d: %+v", len(users), users)
+	}
+
+	byID := map[string]*access.AccessUser{}
+	for _, u := range users {
+		byID[u.UserID] = u
+	}
+
+	if byID["alice"] == nil || byID["alice"].FobID != 1234 {
+		t.Fatalf("expected alice with fobID 1234, got %+v", byID["alice"])
+	}
+	bob := byID["bob"]
+	if bob == nil {
+		t.Fatal("expected bob to still sync despite the invalid fobTTL attribute")
+	}
+	if bob.TTL != (24 * time.Hour).Milliseconds() {
+		t.Fatalf("expected bob to fall back to the group's default TTL, got %d", bob.TTL)
+	}
+	if _, ok := byID["carol"]; ok {
+		t.Fatal("expected carol to be excluded, since she has no buildingAccessApprover")
+	}
+}
+
+func TestListUsersGroupError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/Groups/engineers", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	src := New(srv.URL, "", []GroupConfig{{ID: "engineers", DefaultTTL: time.Hour}})
+	if _, err := src.ListUsers(context.Background()); err == nil {
+		t.Fatal("expected an error when the group lookup itself fails")
+	}
+}